@@ -2,200 +2,57 @@ package main
 
 import (
 	"fmt"
-	"sync"
-)
-
-type OrderType int
+	"time"
 
-const (
-	LimitOrder  OrderType = iota // 限价单
-	MarketOrder                  // 市价单
+	"yxengine/trade-match/order_book"
 )
 
-type Order struct {
-	ID       int
-	Type     OrderType
-	Price    float64
-	Amount   float64
-	Priority int
-}
-
-type OrderBook struct {
-	BuyOrders  []Order
-	SellOrders []Order
-}
-
-func (ob *OrderBook) AddBuyOrder(order Order) {
-	ob.BuyOrders = append(ob.BuyOrders, order)
-}
-
-func (ob *OrderBook) AddSellOrder(order Order) {
-	ob.SellOrders = append(ob.SellOrders, order)
-}
-
-func (ob *OrderBook) CancelBuyOrder(orderID int) {
-	for i, order := range ob.BuyOrders {
-		if order.ID == orderID {
-			ob.BuyOrders = append(ob.BuyOrders[:i], ob.BuyOrders[i+1:]...)
-			break
-		}
-	}
-}
-
-func (ob *OrderBook) CancelSellOrder(orderID int) {
-	for i, order := range ob.SellOrders {
-		if order.ID == orderID {
-			ob.SellOrders = append(ob.SellOrders[:i], ob.SellOrders[i+1:]...)
-			break
-		}
-	}
-}
-
-func (ob *OrderBook) MatchOrders() {
-	tradeChannel := make(chan Order)
-	wg := sync.WaitGroup{}
-
-	// 启动撮合协程
-	wg.Add(1)
-	go ob.processMatchOrders(tradeChannel, &wg)
-
-	// 将买单和卖单发送到撮合协程进行撮合
-	for _, buyOrder := range ob.BuyOrders {
-		for _, sellOrder := range ob.SellOrders {
-			if ob.shouldMatch(buyOrder, sellOrder) {
-				// 发送撮合订单到通道
-				tradeChannel <- Order{
-					ID:       buyOrder.ID,
-					Type:     buyOrder.Type,
-					Price:    sellOrder.Price,
-					Amount:   sellOrder.Amount,
-					Priority: buyOrder.Priority,
-				}
-			}
-		}
-	}
-
-	// 关闭通道，表示撮合结束
-	close(tradeChannel)
-
-	wg.Wait()
-}
-
-func (ob *OrderBook) processMatchOrders(tradeChannel <-chan Order, wg *sync.WaitGroup) {
-	defer wg.Done()
-
-	for tradeOrder := range tradeChannel {
-		// 找到对应的买单和卖单
-		var buyOrder, sellOrder *Order
-		for i := range ob.BuyOrders {
-			if ob.BuyOrders[i].ID == tradeOrder.ID {
-				buyOrder = &ob.BuyOrders[i]
-				break
-			}
-		}
-		for i := range ob.SellOrders {
-			if ob.SellOrders[i].Price == tradeOrder.Price && ob.SellOrders[i].Amount == tradeOrder.Amount {
-				sellOrder = &ob.SellOrders[i]
-				break
-			}
-		}
-
-		if buyOrder == nil || sellOrder == nil {
-			continue
-		}
-
-		// 处理市价单的情况
-		if buyOrder.Type == MarketOrder {
-			buyOrder.Price = sellOrder.Price
-		} else if sellOrder.Type == MarketOrder {
-			sellOrder.Price = buyOrder.Price
-		}
-
-		// 计算成交数量
-		tradeAmount := sellOrder.Amount
-		if buyOrder.Amount < sellOrder.Amount {
-			tradeAmount = buyOrder.Amount
-		}
-
-		// 输出成交信息
-		fmt.Printf("Trade: Buy Order %d and Sell Order %d at Price %.2f, Amount %.2f\n",
-			buyOrder.ID, sellOrder.ID, tradeOrder.Price, tradeAmount)
-
-		// 更新订单数量
-		buyOrder.Amount -= tradeAmount
-		sellOrder.Amount -= tradeAmount
-
-		// 移除数量为0的订单
-		if buyOrder.Amount == 0 {
-			ob.CancelBuyOrder(buyOrder.ID)
-		}
-		if sellOrder.Amount == 0 {
-			ob.CancelSellOrder(sellOrder.ID)
-		}
-	}
-}
-
-func (ob *OrderBook) shouldMatch(buyOrder, sellOrder Order) bool {
-	if buyOrder.Price >= sellOrder.Price {
-		if buyOrder.Type == MarketOrder || sellOrder.Type == MarketOrder {
-			return true
-		} else {
-			return buyOrder.Price >= sellOrder.Price
-		}
-	}
-	return false
-}
-
+// main is a small runnable demo of the order_book package: rest some sell
+// orders, submit a taker buy order against them, print the resulting
+// trades and book state, then cancel what's left.
 func main() {
-	orderBook := OrderBook{}
+	const productID = 1
 
-	// 添加测试用例
-	orderBook.AddBuyOrder(Order{ID: 1, Type: LimitOrder, Price: 10.0, Amount: 5.0, Priority: 5})
-	orderBook.AddBuyOrder(Order{ID: 2, Type: MarketOrder, Price: 0, Amount: 3.0, Priority: 3})
-	orderBook.AddBuyOrder(Order{ID: 3, Type: LimitOrder, Price: 12.0, Amount: 7.0, Priority: 8})
+	serializer := order_book.JSONSerializer{}
+	ob := order_book.NewOrderBook(serializer, 0.05)
 
-	orderBook.AddSellOrder(Order{ID: 4, Type: LimitOrder, Price: 11.5, Amount: 10.0, Priority: 4})
-	orderBook.AddSellOrder(Order{ID: 5, Type: MarketOrder, Price: 0, Amount: 5.0, Priority: 6})
+	// 挂出卖单，构建盘口
+	ob.AddSellOrder(order_book.Order{ID: 1, Type: order_book.LimitOrder, Price: 11.5, Amount: 10.0, Priority: 4, CreateTime: time.Now(), ProductID: productID})
+	ob.AddSellOrder(order_book.Order{ID: 2, Type: order_book.LimitOrder, Price: 12.0, Amount: 5.0, Priority: 6, CreateTime: time.Now(), ProductID: productID})
 
-	// 打印订单簿状态
 	fmt.Println("Initial Order Book:")
-	fmt.Println("Buy Orders:")
-	for _, order := range orderBook.BuyOrders {
-		fmt.Printf("ID: %d, Type: %v, Price: %.2f, Amount: %.2f, Priority: %d\n", order.ID, order.Type, order.Price, order.Amount, order.Priority)
-	}
-	fmt.Println("Sell Orders:")
-	for _, order := range orderBook.SellOrders {
-		fmt.Printf("ID: %d, Type: %v, Price: %.2f, Amount: %.2f, Priority: %d\n", order.ID, order.Type, order.Price, order.Amount, order.Priority)
-	}
+	printBook(ob, productID)
 	fmt.Println()
 
-	// 进行撮合操作
-	orderBook.MatchOrders()
+	// 提交一笔吃单买单，按价格-时间优先撮合
+	taker := order_book.Order{ID: 3, Type: order_book.LimitOrder, Price: 12.0, Amount: 12.0, Priority: 8, CreateTime: time.Now(), ProductID: productID}
+	trades := ob.MatchOrders(productID, taker, true)
 
-	// 打印撮合后的订单簿状态
-	fmt.Println("Final Order Book:")
-	fmt.Println("Buy Orders:")
-	for _, order := range orderBook.BuyOrders {
-		fmt.Printf("ID: %d, Type: %v, Price: %.2f, Amount: %.2f, Priority: %d\n", order.ID, order.Type, order.Price, order.Amount, order.Priority)
-	}
-	fmt.Println("Sell Orders:")
-	for _, order := range orderBook.SellOrders {
-		fmt.Printf("ID: %d, Type: %v, Price: %.2f, Amount: %.2f, Priority: %d\n", order.ID, order.Type, order.Price, order.Amount, order.Priority)
+	fmt.Println("Trades:")
+	for _, trade := range trades {
+		fmt.Printf("Taker %d filled by Maker %d at Price %.2f, Amount %.2f\n",
+			trade.TakerID, trade.MakerID, trade.Price, trade.Amount)
 	}
 	fmt.Println()
 
-	// 撤销订单
-	orderBook.CancelBuyOrder(2)
-	orderBook.CancelSellOrder(5)
+	fmt.Println("Order Book after Matching:")
+	printBook(ob, productID)
+	fmt.Println()
+
+	// 撤销剩余的卖单
+	ob.CancelSellOrder(productID, 2)
 
-	// 打印撤销后的订单簿状态
 	fmt.Println("Order Book after Cancellation:")
+	printBook(ob, productID)
+}
+
+func printBook(ob *order_book.OrderBook, productID int) {
 	fmt.Println("Buy Orders:")
-	for _, order := range orderBook.BuyOrders {
+	for _, order := range ob.BuyOrders(productID) {
 		fmt.Printf("ID: %d, Type: %v, Price: %.2f, Amount: %.2f, Priority: %d\n", order.ID, order.Type, order.Price, order.Amount, order.Priority)
 	}
 	fmt.Println("Sell Orders:")
-	for _, order := range orderBook.SellOrders {
+	for _, order := range ob.SellOrders(productID) {
 		fmt.Printf("ID: %d, Type: %v, Price: %.2f, Amount: %.2f, Priority: %d\n", order.ID, order.Type, order.Price, order.Amount, order.Priority)
 	}
 }
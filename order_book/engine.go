@@ -0,0 +1,194 @@
+package order_book
+
+// shardCommandType identifies what a shard's command channel is being
+// asked to do.
+type shardCommandType int
+
+const (
+	shardAddOrder shardCommandType = iota
+	shardCancelOrder
+	shardAmendOrder
+	shardMatchTick
+	shardSnapshotQuery
+)
+
+// shardCommand is one request sent to a shard's single-writer goroutine.
+// reply is buffered by the caller so the send never blocks the shard.
+type shardCommand struct {
+	Type      shardCommandType
+	ProductID int
+	Order     Order
+	IsBuy     bool
+	OrderID   int
+	reply     chan shardResult
+}
+
+type shardResult struct {
+	Trades   []Trade
+	Snapshot BookSnapshot
+}
+
+// shard owns a disjoint slice of products behind its own OrderBook and a
+// bounded command channel. Because every command for a given product
+// always lands on the same shard and that shard drains its channel from a
+// single goroutine, products on different shards never contend with each
+// other the way they would serialize behind one global mutex.
+type shard struct {
+	book     *OrderBook
+	commands chan shardCommand
+	done     chan struct{}
+}
+
+func newShard(serializer Serializer, priceTolerance float64, bufferSize int) *shard {
+	s := &shard{
+		book:     NewOrderBook(serializer, priceTolerance),
+		commands: make(chan shardCommand, bufferSize),
+		done:     make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *shard) run() {
+	defer close(s.done)
+	for cmd := range s.commands {
+		s.handle(cmd)
+	}
+}
+
+func (s *shard) handle(cmd shardCommand) {
+	switch cmd.Type {
+	case shardAddOrder:
+		if cmd.IsBuy {
+			s.book.AddBuyOrder(cmd.Order)
+		} else {
+			s.book.AddSellOrder(cmd.Order)
+		}
+		cmd.reply <- shardResult{}
+
+	case shardCancelOrder:
+		if cmd.IsBuy {
+			s.book.CancelBuyOrder(cmd.ProductID, cmd.OrderID)
+		} else {
+			s.book.CancelSellOrder(cmd.ProductID, cmd.OrderID)
+		}
+		cmd.reply <- shardResult{}
+
+	case shardAmendOrder:
+		// Amend is cancel-then-rest-at-the-new-terms: the simplest
+		// semantics that still leaves the rest of the book untouched.
+		// (An amend that only shrinks size could keep queue position
+		// instead; this engine doesn't distinguish that case.)
+		if cmd.IsBuy {
+			s.book.CancelBuyOrder(cmd.ProductID, cmd.OrderID)
+			s.book.AddBuyOrder(cmd.Order)
+		} else {
+			s.book.CancelSellOrder(cmd.ProductID, cmd.OrderID)
+			s.book.AddSellOrder(cmd.Order)
+		}
+		cmd.reply <- shardResult{}
+
+	case shardMatchTick:
+		trades := s.book.MatchOrders(cmd.ProductID, cmd.Order, cmd.IsBuy)
+		cmd.reply <- shardResult{Trades: trades}
+
+	case shardSnapshotQuery:
+		cmd.reply <- shardResult{Snapshot: s.book.Snapshot(cmd.ProductID)}
+	}
+}
+
+func (s *shard) stop() {
+	close(s.commands)
+	<-s.done
+}
+
+// Engine dispatches inbound orders to one of N shards by ProductID % N.
+// Each shard is a single-writer goroutine over its own OrderBook, so
+// Engine scales matching across cores without a lock shared by every
+// symbol — the tradeoff is that there is no consistent snapshot across
+// shards, only per-product ones.
+type Engine struct {
+	shards []*shard
+}
+
+// shardBufferSize bounds each shard's command channel; a shard blocks
+// producers once this many commands are queued rather than growing
+// unboundedly under sustained overload.
+const shardBufferSize = 1024
+
+// NewEngine creates an Engine with shardCount shards (at least 1), each
+// backed by its own OrderBook constructed with serializer and
+// priceTolerance.
+func NewEngine(shardCount int, serializer Serializer, priceTolerance float64) *Engine {
+	if shardCount < 1 {
+		shardCount = 1
+	}
+	shards := make([]*shard, shardCount)
+	for i := range shards {
+		shards[i] = newShard(serializer, priceTolerance, shardBufferSize)
+	}
+	return &Engine{shards: shards}
+}
+
+func (e *Engine) shardFor(productID int) *shard {
+	idx := productID % len(e.shards)
+	if idx < 0 {
+		idx += len(e.shards)
+	}
+	return e.shards[idx]
+}
+
+// AddOrder rests order on productID's book without matching it.
+func (e *Engine) AddOrder(productID int, order Order, isBuy bool) {
+	reply := make(chan shardResult, 1)
+	e.shardFor(productID).commands <- shardCommand{
+		Type: shardAddOrder, ProductID: productID, Order: order, IsBuy: isBuy, reply: reply,
+	}
+	<-reply
+}
+
+// CancelOrder removes orderID from productID's book.
+func (e *Engine) CancelOrder(productID, orderID int, isBuy bool) {
+	reply := make(chan shardResult, 1)
+	e.shardFor(productID).commands <- shardCommand{
+		Type: shardCancelOrder, ProductID: productID, OrderID: orderID, IsBuy: isBuy, reply: reply,
+	}
+	<-reply
+}
+
+// AmendOrder replaces orderID with replacement on productID's book.
+func (e *Engine) AmendOrder(productID, orderID int, isBuy bool, replacement Order) {
+	reply := make(chan shardResult, 1)
+	e.shardFor(productID).commands <- shardCommand{
+		Type: shardAmendOrder, ProductID: productID, OrderID: orderID, IsBuy: isBuy, Order: replacement, reply: reply,
+	}
+	<-reply
+}
+
+// MatchTick submits taker as an incoming order for productID and returns
+// the trades it produced, per MatchOrders' price-time priority rules.
+func (e *Engine) MatchTick(productID int, taker Order, isBuy bool) []Trade {
+	reply := make(chan shardResult, 1)
+	e.shardFor(productID).commands <- shardCommand{
+		Type: shardMatchTick, ProductID: productID, Order: taker, IsBuy: isBuy, reply: reply,
+	}
+	return (<-reply).Trades
+}
+
+// Snapshot returns a consistent view of productID's book, computed on its
+// owning shard's goroutine so it can't race with that shard's own writes.
+func (e *Engine) Snapshot(productID int) BookSnapshot {
+	reply := make(chan shardResult, 1)
+	e.shardFor(productID).commands <- shardCommand{
+		Type: shardSnapshotQuery, ProductID: productID, reply: reply,
+	}
+	return (<-reply).Snapshot
+}
+
+// Close stops every shard's goroutine, waiting for its command channel to
+// drain first.
+func (e *Engine) Close() {
+	for _, s := range e.shards {
+		s.stop()
+	}
+}
@@ -0,0 +1,93 @@
+package order_book
+
+import (
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEngineRoutesAddCancelAmendAndSnapshot(t *testing.T) {
+	serializer := JSONSerializer{}
+	engine := NewEngine(4, serializer, 0.05)
+	defer engine.Close()
+
+	// 产品 1 和产品 2 按 ProductID % 4 会落在不同分片上
+	engine.AddOrder(1, Order{ID: 1, Type: LimitOrder, Price: 9.0, Amount: 3.0, CreateTime: time.Now(), ProductID: 1}, false)
+	engine.AddOrder(2, Order{ID: 2, Type: LimitOrder, Price: 5.0, Amount: 1.0, CreateTime: time.Now(), ProductID: 2}, false)
+
+	snap := engine.Snapshot(1)
+	if len(snap.SellOrders) != 1 || snap.SellOrders[0].ID != 1 {
+		t.Fatalf("Snapshot(1).SellOrders = %+v, want order 1 resting", snap.SellOrders)
+	}
+	if snap := engine.Snapshot(2); len(snap.SellOrders) != 1 || snap.SellOrders[0].ID != 2 {
+		t.Fatalf("Snapshot(2).SellOrders = %+v, want order 2 resting", snap.SellOrders)
+	}
+
+	engine.CancelOrder(1, 1, false)
+	if snap := engine.Snapshot(1); len(snap.SellOrders) != 0 {
+		t.Fatalf("Snapshot(1).SellOrders after cancel = %+v, want empty", snap.SellOrders)
+	}
+
+	engine.AmendOrder(2, 2, false, Order{ID: 2, Type: LimitOrder, Price: 6.0, Amount: 2.0, CreateTime: time.Now(), ProductID: 2})
+	snap = engine.Snapshot(2)
+	if len(snap.SellOrders) != 1 || snap.SellOrders[0].Price != 6.0 || snap.SellOrders[0].Amount != 2.0 {
+		t.Fatalf("Snapshot(2).SellOrders after amend = %+v, want order 2 repriced to 6.0/2.0", snap.SellOrders)
+	}
+
+	trades := engine.MatchTick(2, Order{ID: 3, Type: LimitOrder, Price: 6.0, Amount: 2.0, CreateTime: time.Now(), ProductID: 2}, true)
+	if len(trades) != 1 || trades[0].Amount != 2.0 {
+		t.Fatalf("MatchTick trades = %+v, want one 2.0 fill", trades)
+	}
+}
+
+// BenchmarkEngineMatchOrders extends BenchmarkMatchOrders across many
+// symbols: with one shard per GOMAXPROCS and orders spread over numProducts
+// symbols, matching on different products runs on different shards with no
+// shared lock, so throughput should scale close to linearly with cores.
+func BenchmarkEngineMatchOrders(b *testing.B) {
+	const numProducts = 64
+	shardCount := runtime.GOMAXPROCS(0)
+
+	serializer := JSONSerializer{}
+	engine := NewEngine(shardCount, serializer, 0.05)
+	defer engine.Close()
+
+	// 预先为每个品种挂出卖单构建盘口
+	ordersPerProduct := 10000
+	for productID := 0; productID < numProducts; productID++ {
+		for i := 0; i < ordersPerProduct; i++ {
+			engine.AddOrder(productID, Order{
+				ID:         productID*ordersPerProduct + i,
+				Type:       LimitOrder,
+				Price:      rand.Float64() * 100,
+				Amount:     rand.Float64() * 10,
+				CreateTime: time.Now(),
+				ProductID:  productID,
+			}, false)
+		}
+	}
+
+	b.ResetTimer()
+
+	// nextTakerID is shared across worker goroutines so concurrent workers
+	// hand out distinct order IDs instead of each starting its own sequence
+	// from the same base.
+	nextTakerID := int64(ordersPerProduct * numProducts)
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			takerID := int(atomic.AddInt64(&nextTakerID, 1))
+			productID := takerID % numProducts
+			engine.MatchTick(productID, Order{
+				ID:         takerID,
+				Type:       LimitOrder,
+				Price:      100,
+				Amount:     rand.Float64() * 10,
+				CreateTime: time.Now(),
+				ProductID:  productID,
+			}, true)
+		}
+	})
+}
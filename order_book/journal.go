@@ -0,0 +1,256 @@
+package order_book
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FsyncPolicy controls how aggressively the Journal flushes appended
+// records to durable storage.
+type FsyncPolicy int
+
+const (
+	FsyncNever   FsyncPolicy = iota // leave it to the OS page cache
+	FsyncBatched                    // fsync on a timer, amortizing the cost over many appends
+	FsyncAlways                     // fsync after every single append
+)
+
+// CommandType identifies the kind of mutation a Command journals.
+type CommandType int
+
+const (
+	CmdAddBuyOrder CommandType = iota
+	CmdAddSellOrder
+	CmdCancelBuyOrder
+	CmdCancelSellOrder
+	CmdUpdatePrice
+	CmdTrade
+	CmdAddStop    // a StopOrder/StopLimitOrder was parked in pb.stops
+	CmdRemoveStop // a parked stop was released (activated) or cancelled
+)
+
+// Command is one journaled record. Only the fields relevant to Type are
+// populated; the rest are left at their zero value.
+type Command struct {
+	Seq       uint64
+	Type      CommandType
+	ProductID int
+	OrderID   int     // CmdCancelBuyOrder, CmdCancelSellOrder, CmdRemoveStop
+	Order     Order   // CmdAddBuyOrder, CmdAddSellOrder, CmdAddStop
+	IsBuy     bool    // CmdAddStop
+	NewPrice  float64 // CmdUpdatePrice
+	Trade     Trade   // CmdTrade
+}
+
+// segment is one rotated-out journal file, identified by the sequence
+// number of its first record.
+type segment struct {
+	path     string
+	startSeq uint64
+}
+
+// Journal is an append-only write-ahead log of Commands, used to
+// reconstruct an OrderBook's state via Recover after a restart. Records are
+// length-prefixed and encoded with the caller's Serializer so the on-disk
+// format follows whatever codec (JSON today, a binary one later) the book
+// itself uses.
+type Journal struct {
+	dir        string
+	serializer Serializer
+	policy     FsyncPolicy
+
+	mu                 sync.Mutex
+	file               *os.File
+	writer             *bufio.Writer
+	seq                uint64
+	currentSegmentPath string
+	currentSegmentFrom uint64
+	segments           []segment
+
+	stopBatch chan struct{}
+	batchDone chan struct{}
+}
+
+// OpenJournal opens (or creates) a journal rooted at dir. policy governs
+// how eagerly Append syncs to disk; FsyncBatched starts a background
+// goroutine that flushes on a fixed interval until the Journal is closed.
+func OpenJournal(dir string, serializer Serializer, policy FsyncPolicy) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("order_book: create journal dir: %w", err)
+	}
+
+	path := activeSegmentPath(dir)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("order_book: open journal segment: %w", err)
+	}
+
+	j := &Journal{
+		dir:                dir,
+		serializer:         serializer,
+		policy:             policy,
+		file:               file,
+		writer:             bufio.NewWriter(file),
+		currentSegmentPath: path,
+	}
+
+	if policy == FsyncBatched {
+		j.stopBatch = make(chan struct{})
+		j.batchDone = make(chan struct{})
+		go j.batchFsyncLoop()
+	}
+
+	return j, nil
+}
+
+// activeSegmentPath names the segment currently being written. It sorts
+// after every rotated-out "segment-<seq>.log" (which are zero-padded
+// decimal), so a directory listing walked in name order replays history
+// correctly without needing separate bookkeeping of which file is newest.
+func activeSegmentPath(dir string) string {
+	return filepath.Join(dir, "segment-active.log")
+}
+
+func segmentPath(dir string, startSeq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("segment-%020d.log", startSeq))
+}
+
+// Append writes cmd as the next sequence-numbered record and, depending on
+// policy, syncs it to disk before returning.
+func (j *Journal) Append(cmd Command) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.seq++
+	cmd.Seq = j.seq
+
+	payload, err := j.serializer.Serialize(cmd)
+	if err != nil {
+		return fmt.Errorf("order_book: serialize command: %w", err)
+	}
+
+	var lenPrefix [4]byte
+	binary.BigEndian.PutUint32(lenPrefix[:], uint32(len(payload)))
+	if _, err := j.writer.Write(lenPrefix[:]); err != nil {
+		return err
+	}
+	if _, err := j.writer.Write(payload); err != nil {
+		return err
+	}
+
+	if j.policy == FsyncAlways {
+		return j.flushAndSyncLocked()
+	}
+	return nil
+}
+
+func (j *Journal) flushAndSyncLocked() error {
+	if err := j.writer.Flush(); err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+func (j *Journal) batchFsyncLoop() {
+	defer close(j.batchDone)
+
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			j.mu.Lock()
+			_ = j.flushAndSyncLocked()
+			j.mu.Unlock()
+		case <-j.stopBatch:
+			return
+		}
+	}
+}
+
+// Rotate closes the active segment and starts a new one, recording the
+// closed segment so a later Compact can consider discarding it. Callers
+// typically rotate right after taking a snapshot.
+func (j *Journal) Rotate() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.flushAndSyncLocked(); err != nil {
+		return err
+	}
+	if err := j.file.Close(); err != nil {
+		return err
+	}
+
+	closedPath := segmentPath(j.dir, j.currentSegmentFrom)
+	if err := os.Rename(j.currentSegmentPath, closedPath); err != nil {
+		return fmt.Errorf("order_book: rotate journal segment: %w", err)
+	}
+	j.segments = append(j.segments, segment{path: closedPath, startSeq: j.currentSegmentFrom})
+
+	path := activeSegmentPath(j.dir)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("order_book: open new journal segment: %w", err)
+	}
+
+	j.file = file
+	j.writer = bufio.NewWriter(file)
+	j.currentSegmentPath = path
+	j.currentSegmentFrom = j.seq + 1
+	return nil
+}
+
+// Compact discards any rotated-out segment whose records are all at or
+// below minSeq — i.e. everything a snapshot taken at minSeq has already
+// captured. It's meant to run periodically in the background after
+// Snapshot, not on every write.
+func (j *Journal) Compact(minSeq uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	kept := j.segments[:0]
+	for i, seg := range j.segments {
+		var end uint64
+		if i+1 < len(j.segments) {
+			end = j.segments[i+1].startSeq - 1
+		} else {
+			end = j.currentSegmentFrom - 1
+		}
+		if end <= minSeq {
+			if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("order_book: compact journal segment %s: %w", seg.path, err)
+			}
+			continue
+		}
+		kept = append(kept, seg)
+	}
+	j.segments = kept
+	return nil
+}
+
+// Seq returns the sequence number of the last record appended.
+func (j *Journal) Seq() uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.seq
+}
+
+// Close stops the background fsync goroutine (if any) and flushes the
+// active segment to disk.
+func (j *Journal) Close() error {
+	if j.stopBatch != nil {
+		close(j.stopBatch)
+		<-j.batchDone
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.flushAndSyncLocked()
+}
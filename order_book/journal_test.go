@@ -0,0 +1,54 @@
+package order_book
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecoverReplaysJournalAfterSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	serializer := JSONSerializer{}
+
+	journal, err := OpenJournal(dir, serializer, FsyncAlways)
+	if err != nil {
+		t.Fatalf("OpenJournal: %v", err)
+	}
+
+	orderBook := NewOrderBook(serializer, 0.05)
+	orderBook.Journal = journal
+
+	orderBook.AddSellOrder(Order{ID: 1, Type: LimitOrder, Price: 9.5, Amount: 6.0, CreateTime: time.Now(), ProductID: 1})
+	orderBook.AddSellOrder(Order{ID: 2, Type: LimitOrder, Price: 9.0, Amount: 2.0, CreateTime: time.Now(), ProductID: 1})
+
+	// 打一份快照并滚动日志段
+	snap := orderBook.Snapshot(1)
+	if err := WriteSnapshot(dir, serializer, snap); err != nil {
+		t.Fatalf("WriteSnapshot: %v", err)
+	}
+	if err := journal.Rotate(); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	// 快照之后再追加一笔成交记录
+	taker := Order{ID: 3, Type: LimitOrder, Price: 10.0, Amount: 5.0, CreateTime: time.Now(), ProductID: 1}
+	orderBook.MatchOrders(1, taker, true)
+
+	if err := journal.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	recovered := NewOrderBook(serializer, 0.05)
+	if err := recovered.Recover(dir); err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+
+	sellOrders := recovered.SellOrders(1)
+	var remaining float64
+	for _, o := range sellOrders {
+		remaining += o.Amount
+	}
+	// 8 resting - 5 filled by taker = 3 剩余
+	if got, want := remaining, 3.0; got != want {
+		t.Errorf("remaining sell amount after recovery = %v, want %v", got, want)
+	}
+}
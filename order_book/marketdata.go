@@ -0,0 +1,215 @@
+package order_book
+
+import (
+	"sync"
+	"time"
+)
+
+// DepthEvent is an incremental L2 update: the new aggregate resting
+// quantity at (ProductID, side, Price). NewAggregateQty of 0 means the
+// level was fully removed.
+type DepthEvent struct {
+	ProductID       int
+	IsBid           bool
+	Price           float64
+	NewAggregateQty float64
+}
+
+// TradeEvent is one fill published to the trade tape.
+type TradeEvent struct {
+	ProductID  int
+	Price      float64
+	Qty        float64
+	TakerIsBuy bool
+	Timestamp  time.Time
+	TradeID    uint64
+}
+
+// DepthLevel is one row of a GetDepth snapshot.
+type DepthLevel struct {
+	Price float64
+	Qty   float64
+}
+
+// DepthSnapshot is a top-N view of both sides of a book at one instant.
+type DepthSnapshot struct {
+	ProductID int
+	Bids      []DepthLevel
+	Asks      []DepthLevel
+}
+
+// feedChannelBuffer bounds each subscriber's queue. A subscriber that
+// falls behind drops events past this point rather than blocking the
+// matching loop that's trying to publish them.
+const feedChannelBuffer = 256
+
+// marketDataFeed fans depth and trade events out to per-product
+// subscribers. It's created lazily by the first Subscribe call, so a book
+// nobody subscribes to pays nothing for it.
+type marketDataFeed struct {
+	mu        sync.Mutex
+	nextSubID int
+	tradeSeq  uint64
+	depth     map[int]map[int]chan DepthEvent
+	trades    map[int]map[int]chan TradeEvent
+}
+
+func newMarketDataFeed() *marketDataFeed {
+	return &marketDataFeed{
+		depth:  make(map[int]map[int]chan DepthEvent),
+		trades: make(map[int]map[int]chan TradeEvent),
+	}
+}
+
+// Subscribe returns a depth stream and a trade stream for productID, and a
+// cancel func that unregisters and closes both. Publishing never blocks on
+// a subscriber: a channel that's full just drops the event.
+func (ob *OrderBook) Subscribe(productID int) (<-chan DepthEvent, <-chan TradeEvent, func()) {
+	ob.mutex.Lock()
+	if ob.feed == nil {
+		ob.feed = newMarketDataFeed()
+	}
+	feed := ob.feed
+	ob.mutex.Unlock()
+
+	return feed.subscribe(productID)
+}
+
+func (f *marketDataFeed) subscribe(productID int) (<-chan DepthEvent, <-chan TradeEvent, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextSubID++
+	id := f.nextSubID
+
+	depthCh := make(chan DepthEvent, feedChannelBuffer)
+	tradeCh := make(chan TradeEvent, feedChannelBuffer)
+
+	if f.depth[productID] == nil {
+		f.depth[productID] = make(map[int]chan DepthEvent)
+	}
+	if f.trades[productID] == nil {
+		f.trades[productID] = make(map[int]chan TradeEvent)
+	}
+	f.depth[productID][id] = depthCh
+	f.trades[productID][id] = tradeCh
+
+	cancel := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if ch, ok := f.depth[productID][id]; ok {
+			delete(f.depth[productID], id)
+			close(ch)
+		}
+		if ch, ok := f.trades[productID][id]; ok {
+			delete(f.trades[productID], id)
+			close(ch)
+		}
+	}
+
+	return depthCh, tradeCh, cancel
+}
+
+func (f *marketDataFeed) publishDepth(evt DepthEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, ch := range f.depth[evt.ProductID] {
+		select {
+		case ch <- evt:
+		default:
+		}
+	}
+}
+
+func (f *marketDataFeed) publishTrade(productID int, evt TradeEvent) {
+	f.mu.Lock()
+	f.tradeSeq++
+	evt.TradeID = f.tradeSeq
+	subs := f.trades[productID]
+	f.mu.Unlock()
+
+	for _, ch := range subs {
+		select {
+		case ch <- evt:
+		default:
+		}
+	}
+}
+
+// publishDepth looks up the current aggregate quantity at (productID,
+// isBid, price) and publishes it — 0 if the level no longer exists. Safe
+// to call with no subscribers (and no feed at all): it's then a no-op.
+func (ob *OrderBook) publishDepth(productID int, isBid bool, price float64) {
+	if ob.feed == nil {
+		return
+	}
+
+	var qty float64
+	if pb, ok := ob.books[productID]; ok {
+		side := pb.asks
+		if isBid {
+			side = pb.bids
+		}
+		if level, ok := side.byPrice[price]; ok {
+			qty = level.total
+		}
+	}
+
+	ob.feed.publishDepth(DepthEvent{ProductID: productID, IsBid: isBid, Price: price, NewAggregateQty: qty})
+}
+
+func (ob *OrderBook) publishTrade(productID int, trade Trade, takerIsBuy bool) {
+	if ob.feed == nil {
+		return
+	}
+	ob.feed.publishTrade(productID, TradeEvent{
+		ProductID:  productID,
+		Price:      trade.Price,
+		Qty:        trade.Amount,
+		TakerIsBuy: takerIsBuy,
+		Timestamp:  trade.Time,
+	})
+}
+
+// GetDepth walks productID's tree from best price outward on each side,
+// returning up to levels rows per side.
+func (ob *OrderBook) GetDepth(productID int, levels int) DepthSnapshot {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	pb, ok := ob.books[productID]
+	if !ok {
+		return DepthSnapshot{ProductID: productID}
+	}
+
+	return DepthSnapshot{
+		ProductID: productID,
+		Bids:      topLevels(pb.bids, levels),
+		Asks:      topLevels(pb.asks, levels),
+	}
+}
+
+func topLevels(side *bookSide, levels int) []DepthLevel {
+	var out []DepthLevel
+	for level := side.best; level != nil && len(out) < levels; {
+		out = append(out, DepthLevel{Price: level.price, Qty: level.total})
+		if side.isBid {
+			level = treePredecessor(level)
+		} else {
+			level = treeSuccessor(level)
+		}
+	}
+	return out
+}
+
+// EncodeDepthEvent and EncodeTradeEvent serialize outbound events through
+// ob.Serializer, so a binary codec swapped in later works for market data
+// the same way it already does for the journal.
+func (ob *OrderBook) EncodeDepthEvent(evt DepthEvent) ([]byte, error) {
+	return ob.Serializer.Serialize(evt)
+}
+
+func (ob *OrderBook) EncodeTradeEvent(evt TradeEvent) ([]byte, error) {
+	return ob.Serializer.Serialize(evt)
+}
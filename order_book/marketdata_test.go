@@ -0,0 +1,51 @@
+package order_book
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribePublishesDepthAndTrades(t *testing.T) {
+	serializer := JSONSerializer{}
+	orderBook := NewOrderBook(serializer, 0.05)
+
+	depthCh, tradeCh, cancel := orderBook.Subscribe(1)
+	defer cancel()
+
+	orderBook.AddSellOrder(Order{ID: 1, Type: LimitOrder, Price: 9.0, Amount: 4.0, CreateTime: time.Now(), ProductID: 1})
+
+	select {
+	case evt := <-depthCh:
+		if evt.IsBid || evt.Price != 9.0 || evt.NewAggregateQty != 4.0 {
+			t.Errorf("unexpected depth event after resting: %+v", evt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for depth event")
+	}
+
+	taker := Order{ID: 2, Type: LimitOrder, Price: 10.0, Amount: 4.0, CreateTime: time.Now(), ProductID: 1}
+	orderBook.MatchOrders(1, taker, true)
+
+	select {
+	case evt := <-tradeCh:
+		if evt.Price != 9.0 || evt.Qty != 4.0 || !evt.TakerIsBuy {
+			t.Errorf("unexpected trade event: %+v", evt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for trade event")
+	}
+
+	select {
+	case evt := <-depthCh:
+		if evt.NewAggregateQty != 0 {
+			t.Errorf("expected level removal after full fill, got %+v", evt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for depth removal event")
+	}
+
+	depth := orderBook.GetDepth(1, 5)
+	if len(depth.Asks) != 0 {
+		t.Errorf("GetDepth asks = %+v, want empty after full fill", depth.Asks)
+	}
+}
@@ -0,0 +1,152 @@
+package order_book
+
+import "time"
+
+// Trade is one fill produced by the matching loop: the amount of a resting
+// maker order consumed by an incoming taker order, at the maker's price.
+type Trade struct {
+	TakerID int
+	MakerID int
+	Price   float64
+	Amount  float64
+	Time    time.Time
+}
+
+// MatchOrders takes taker, an incoming order for productID on the given
+// side, and walks the opposite side of the book from its best price
+// outward, filling against resting makers in strict price-time priority:
+// levels are consumed best-price-first, and within a level orders are
+// consumed head-first (oldest first).
+//
+// Partial fills decrement amounts in place; a maker that reaches zero is
+// removed via the side's O(1) order index (or reloaded, for an iceberg
+// maker). Any leftover taker quantity rests on the book if taker is a
+// limit order; for a market order the remainder is discarded rather than
+// resting, since MatchOrders never itself creates a resting market order.
+//
+// MatchOrders is the unconditional matching primitive: it always fills
+// what it can and always rests the remainder of a limit taker. Order types
+// with placement preconditions (IOC, FOK, post-only, stop, iceberg) are
+// handled by SubmitOrder, which wraps this loop with the appropriate
+// checks before and after.
+func (ob *OrderBook) MatchOrders(productID int, taker Order, isBuy bool) []Trade {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	pb := ob.productBook(productID)
+	return ob.matchLocked(pb, productID, taker, isBuy, true)
+}
+
+// matchLocked is MatchOrders' body, callable while ob.mutex is already
+// held. rest controls whether a limit taker's remainder is placed on the
+// book afterwards; SubmitOrder passes false for IOC/FOK so it can apply
+// its own rest-or-cancel decision.
+func (ob *OrderBook) matchLocked(pb *productBook, productID int, taker Order, isBuy bool, rest bool) []Trade {
+	var makers, restingSide *bookSide
+	var restCmd CommandType
+	if isBuy {
+		makers = pb.asks
+		restingSide = pb.bids
+		restCmd = CmdAddBuyOrder
+	} else {
+		makers = pb.bids
+		restingSide = pb.asks
+		restCmd = CmdAddSellOrder
+	}
+
+	trades := fillAgainst(makers, &taker, time.Now())
+	for _, trade := range trades {
+		pb.lastTradePrice = trade.Price
+		pb.hasTraded = true
+		ob.journal(Command{Type: CmdTrade, ProductID: productID, Trade: trade})
+		ob.publishDepth(productID, makers.isBid, trade.Price)
+		ob.publishTrade(productID, trade, isBuy)
+	}
+
+	if rest && taker.Amount > 0 && taker.Type != MarketOrder {
+		restingSide.insert(taker)
+		ob.journal(Command{Type: restCmd, ProductID: productID, Order: taker})
+		ob.publishDepth(productID, restingSide.isBid, taker.Price)
+	}
+
+	if len(trades) > 0 {
+		ob.activateStops(pb, productID)
+	}
+
+	return trades
+}
+
+// fillAgainst drains makers' best levels into taker until either taker is
+// exhausted or the book no longer crosses taker's limit price (a market
+// taker always crosses, by construction, until the book is empty). executedAt
+// is stamped on every resulting Trade: it's when the match ran, not when the
+// maker it filled against was originally placed.
+func fillAgainst(makers *bookSide, taker *Order, executedAt time.Time) []Trade {
+	var trades []Trade
+
+	for taker.Amount > 0 {
+		level := makers.best
+		if level == nil {
+			break
+		}
+		if taker.Type != MarketOrder && !crosses(*taker, level.price, makers.isBid) {
+			break
+		}
+
+		maker := level.head
+		fillAmount := maker.order.Amount
+		if taker.Amount < fillAmount {
+			fillAmount = taker.Amount
+		}
+
+		trades = append(trades, Trade{
+			TakerID: taker.ID,
+			MakerID: maker.order.ID,
+			Price:   level.price,
+			Amount:  fillAmount,
+			Time:    executedAt,
+		})
+
+		taker.Amount -= fillAmount
+		maker.order.Amount -= fillAmount
+		level.total -= fillAmount
+
+		if maker.order.Amount <= 0 {
+			reloadIceberg(makers, maker)
+		}
+	}
+
+	return trades
+}
+
+// reloadIceberg removes a fully-filled maker from the book, unless it is an
+// iceberg slice with HiddenAmount left: then it reloads the next slice and
+// re-queues it at the tail of its price level, so the newly revealed
+// quantity takes the back of the time-priority line rather than keeping
+// the exhausted slice's place.
+func reloadIceberg(makers *bookSide, maker *orderElement) {
+	order := maker.order
+	if order.HiddenAmount <= 0 {
+		makers.removeOrder(order.ID)
+		return
+	}
+
+	makers.removeOrder(order.ID)
+
+	order.Amount = order.DisplayAmount
+	if order.HiddenAmount < order.Amount {
+		order.Amount = order.HiddenAmount
+	}
+	order.HiddenAmount -= order.Amount
+	makers.insert(order)
+}
+
+// crosses reports whether taker's limit price would trade against a resting
+// order at levelPrice. makersAreBids is true when taker is a sell walking
+// the bid side (a sell crosses when its price is at or below the bid).
+func crosses(taker Order, levelPrice float64, makersAreBids bool) bool {
+	if makersAreBids {
+		return taker.Price <= levelPrice
+	}
+	return taker.Price >= levelPrice
+}
@@ -2,8 +2,9 @@ package order_book
 
 import (
 	"encoding/json"
-	"fmt"
+	"log"
 	"math"
+	"sort"
 	"sync"
 	"time"
 )
@@ -26,8 +27,23 @@ func (s JSONSerializer) Deserialize(data []byte, v interface{}) error {
 type OrderType int
 
 const (
-	LimitOrder  OrderType = iota // 限价单
-	MarketOrder                  // 市价单
+	LimitOrder     OrderType = iota // 限价单
+	MarketOrder                     // 市价单
+	IOCOrder                        // 立即成交剩余撤销
+	FOKOrder                        // 全部成交否则撤销
+	PostOnlyOrder                   // 只做 Maker，会吃单则拒绝
+	StopOrder                       // 市价止损/止盈，触发后转为 MarketOrder
+	StopLimitOrder                  // 限价止损/止盈，触发后转为 LimitOrder
+	IcebergOrder                    // 冰山单，只在盘口展示 DisplayAmount
+)
+
+// RejectReason explains why SubmitOrder refused to place an order.
+type RejectReason int
+
+const (
+	RejectNone            RejectReason = iota
+	RejectFillOrKill                   // FOK order could not be fully filled immediately
+	RejectPostOnlyCrossed              // post-only order would have taken liquidity
 )
 
 type Order struct {
@@ -38,59 +54,130 @@ type Order struct {
 	Priority   int
 	CreateTime time.Time
 	ProductID  int
+
+	// StopPrice is the trigger for StopOrder/StopLimitOrder: the order is
+	// held off-book until the product's last trade price crosses it, then
+	// it is released as a MarketOrder (StopOrder) or LimitOrder (StopLimitOrder).
+	StopPrice float64
+
+	// DisplayAmount and HiddenAmount describe an IcebergOrder: only
+	// DisplayAmount rests on the book at a time. Amount tracks the current
+	// visible slice; when it is fully filled, HiddenAmount reloads it.
+	DisplayAmount float64
+	HiddenAmount  float64
+}
+
+// productBook is one product's book: a bid side and an ask side, each a
+// price-indexed red-black tree of FIFO price levels. Keeping the two sides
+// separate mirrors how they're traded (buy vs sell) and how they're walked
+// during matching (best bid down, best ask up).
+type productBook struct {
+	bids *bookSide
+	asks *bookSide
+
+	lastTradePrice float64
+	hasTraded      bool
+
+	// stops holds StopOrder/StopLimitOrder instances waiting to be
+	// activated by lastTradePrice crossing their StopPrice. isBuy is kept
+	// alongside each order since Order itself doesn't carry a side.
+	stops []pendingStop
 }
 
+// pendingStop's fields are exported so a BookSnapshot (see recovery.go) can
+// serialize it through the pluggable Serializer.
+type pendingStop struct {
+	Order Order
+	IsBuy bool
+}
+
+func newProductBook() *productBook {
+	return &productBook{
+		bids: newBookSide(true),
+		asks: newBookSide(false),
+	}
+}
+
+// OrderBook indexes one productBook per ProductID. All mutating operations
+// take the book-wide mutex; per-product sharding is handled by Engine
+// (see engine.go) for callers that need to scale across symbols.
 type OrderBook struct {
-	BuyOrders     map[int][]Order
-	SellOrders    map[int][]Order
+	mutex          sync.Mutex
+	books          map[int]*productBook
 	PriceTolerance float64
-	mutex         sync.Mutex
-	Serializer    Serializer
-}
+	Serializer     Serializer
 
-type OrderQueue struct {
-	Orders []Order
+	// Journal, if set, receives a Command for every mutating call so the
+	// book can be reconstructed later via Recover. Nil by default: a book
+	// with no journal simply isn't durable. See journal.go.
+	Journal *Journal
+
+	// feed fans out depth and trade events to Subscribe callers. It's
+	// created lazily by the first Subscribe call. See marketdata.go.
+	feed *marketDataFeed
 }
 
 func NewOrderBook(serializer Serializer, priceTolerance float64) *OrderBook {
 	return &OrderBook{
-		BuyOrders:     make(map[int][]Order),
-		SellOrders:    make(map[int][]Order),
+		books:          make(map[int]*productBook),
 		PriceTolerance: priceTolerance,
-		Serializer:    serializer,
+		Serializer:     serializer,
 	}
 }
 
+func (ob *OrderBook) productBook(productID int) *productBook {
+	pb, ok := ob.books[productID]
+	if !ok {
+		pb = newProductBook()
+		ob.books[productID] = pb
+	}
+	return pb
+}
+
 func (ob *OrderBook) AddBuyOrder(order Order) {
 	ob.mutex.Lock()
 	defer ob.mutex.Unlock()
 
-	ob.insertOrder(ob.BuyOrders, order)
+	ob.productBook(order.ProductID).bids.insert(order)
+	ob.journal(Command{Type: CmdAddBuyOrder, ProductID: order.ProductID, Order: order})
+	ob.publishDepth(order.ProductID, true, order.Price)
 }
 
 func (ob *OrderBook) AddSellOrder(order Order) {
 	ob.mutex.Lock()
 	defer ob.mutex.Unlock()
 
-	ob.insertOrder(ob.SellOrders, order)
+	ob.productBook(order.ProductID).asks.insert(order)
+	ob.journal(Command{Type: CmdAddSellOrder, ProductID: order.ProductID, Order: order})
+	ob.publishDepth(order.ProductID, false, order.Price)
 }
 
-func (ob *OrderBook) insertOrder(orderMap map[int][]Order, order Order) {
-	orders := orderMap[order.ProductID]
-	orders = append(orders, order)
-	orderMap[order.ProductID] = orders
+// journal appends cmd to ob.Journal if one is attached. A journal write
+// failure is logged rather than propagated: losing durability on a single
+// command shouldn't stop the matching engine, it only narrows Recover's
+// guarantee to the last record that made it to disk.
+func (ob *OrderBook) journal(cmd Command) {
+	if ob.Journal == nil {
+		return
+	}
+	if err := ob.Journal.Append(cmd); err != nil {
+		log.Printf("order_book: journal append failed: %v", err)
+	}
 }
 
+// CancelBuyOrder unlinks orderID from productID's bid side in O(1), plus an
+// O(log n) tree removal if that was the last order resting at its price.
 func (ob *OrderBook) CancelBuyOrder(productID, orderID int) {
 	ob.mutex.Lock()
 	defer ob.mutex.Unlock()
 
-	orders := ob.BuyOrders[productID]
-	for i, order := range orders {
-		if order.ID == orderID {
-			ob.BuyOrders[productID] = append(orders[:i], orders[i+1:]...)
-			break
-		}
+	pb, ok := ob.books[productID]
+	if !ok {
+		return
+	}
+	if canceled, ok := pb.bids.removeOrder(orderID); ok {
+		ob.journal(Command{Type: CmdCancelBuyOrder, ProductID: productID, OrderID: orderID})
+		ob.publishDepth(productID, true, canceled.Price)
 	}
 }
 
@@ -98,180 +185,162 @@ func (ob *OrderBook) CancelSellOrder(productID, orderID int) {
 	ob.mutex.Lock()
 	defer ob.mutex.Unlock()
 
-	orders := ob.SellOrders[productID]
-	for i, order := range orders {
-		if order.ID == orderID {
-			ob.SellOrders[productID] = append(orders[:i], orders[i+1:]...)
-			break
-		}
+	pb, ok := ob.books[productID]
+	if !ok {
+		return
+	}
+	if canceled, ok := pb.asks.removeOrder(orderID); ok {
+		ob.journal(Command{Type: CmdCancelSellOrder, ProductID: productID, OrderID: orderID})
+		ob.publishDepth(productID, false, canceled.Price)
 	}
 }
 
-func (ob *OrderBook) MatchOrders(productID int) {
+// BestBid and BestAsk read the cached top-of-book pointers, O(1).
+func (ob *OrderBook) BestBid(productID int) (float64, bool) {
 	ob.mutex.Lock()
 	defer ob.mutex.Unlock()
 
-	tradeChannel := make(chan Order)
-	wg := sync.WaitGroup{}
-
-	// 启动撮合协程
-	wg.Add(1)
-	go ob.processMatchOrders(productID, tradeChannel, &wg)
-
-	// 将买单和卖单发送到撮合协程进行撮合
-	buyOrders := ob.BuyOrders[productID]
-	sellOrders := ob.SellOrders[productID]
-	for _, buyOrder := range buyOrders {
-		for _, sellOrder := range sellOrders {
-			if ob.shouldMatch(buyOrder, sellOrder) {
-				tradeChannel <- Order{
-					ID:         buyOrder.ID,
-					Type:       buyOrder.Type,
-					Price:      buyOrder.Price,
-					Amount:     buyOrder.Amount,
-					Priority:   buyOrder.Priority,
-					CreateTime: buyOrder.CreateTime,
-					ProductID:  productID,
-				}
-				tradeChannel <- Order{
-					ID:         sellOrder.ID,
-					Type:       sellOrder.Type,
-					Price:      sellOrder.Price,
-					Amount:     sellOrder.Amount,
-					Priority:   sellOrder.Priority,
-					CreateTime: sellOrder.CreateTime,
-					ProductID:  productID,
-				}
-			}
-		}
+	pb, ok := ob.books[productID]
+	if !ok {
+		return 0, false
 	}
-
-	// 关闭撮合通道，等待撮合协程结束
-	close(tradeChannel)
-	wg.Wait()
+	return pb.bids.BestPrice()
 }
 
-func (ob *OrderBook) processMatchOrders(productID int, tradeChannel <-chan Order, wg *sync.WaitGroup) {
-	defer wg.Done()
-
-	for tradeOrder := range tradeChannel {
-		ob.mutex.Lock()
-
-		// 找到对应的买单和卖单
-		var buyOrder, sellOrder *Order
-		for i := range ob.BuyOrders[productID] {
-			if ob.BuyOrders[productID][i].ID == tradeOrder.ID {
-				buyOrder = &ob.BuyOrders[productID][i]
-				break
-			}
-		}
-		for i := range ob.SellOrders[productID] {
-			if ob.SellOrders[productID][i].ID == tradeOrder.ID {
-				sellOrder = &ob.SellOrders[productID][i]
-				break
-			}
-		}
-
-		if buyOrder == nil || sellOrder == nil {
-			ob.mutex.Unlock()
-			continue
-		}
-
-		// 处理市价单的情况
-		if buyOrder.Type == MarketOrder {
-			buyOrder.Price = sellOrder.Price
-		} else if sellOrder.Type == MarketOrder {
-			sellOrder.Price = buyOrder.Price
-		}
+func (ob *OrderBook) BestAsk(productID int) (float64, bool) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
 
-		// 计算成交数量
-		tradeAmount := sellOrder.Amount
-		if buyOrder.Amount < sellOrder.Amount {
-			tradeAmount = buyOrder.Amount
-		}
+	pb, ok := ob.books[productID]
+	if !ok {
+		return 0, false
+	}
+	return pb.asks.BestPrice()
+}
 
-		// 输出成交信息
-		fmt.Printf("Trade: Buy Order %d and Sell Order %d for Product %d at Price %.2f, Amount %.2f\n",
-			buyOrder.ID, sellOrder.ID, tradeOrder.ProductID, tradeOrder.Price, tradeAmount)
+// BuyOrders and SellOrders return a price-time-ordered snapshot of the
+// resting orders on each side, for callers (tests, printing) that want the
+// old slice-shaped view without reaching into the tree themselves.
+func (ob *OrderBook) BuyOrders(productID int) []Order {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
 
-		// 更新订单数量
-		buyOrder.Amount -= tradeAmount
-		sellOrder.Amount -= tradeAmount
+	pb, ok := ob.books[productID]
+	if !ok {
+		return nil
+	}
+	return snapshotSide(pb.bids, true)
+}
 
-		// 移除数量为0的订单
-		if buyOrder.Amount == 0 {
-			ob.CancelBuyOrder(productID, buyOrder.ID)
-		}
-		if sellOrder.Amount == 0 {
-			ob.CancelSellOrder(productID, sellOrder.ID)
-		}
+func (ob *OrderBook) SellOrders(productID int) []Order {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
 
-		ob.mutex.Unlock()
+	pb, ok := ob.books[productID]
+	if !ok {
+		return nil
 	}
+	return snapshotSide(pb.asks, false)
 }
 
-func (ob *OrderBook) shouldMatch(buyOrder, sellOrder Order) bool {
-	if buyOrder.Price >= sellOrder.Price {
-		if buyOrder.Type == MarketOrder || sellOrder.Type == MarketOrder {
-			return true
+// snapshotSide walks the tree in price priority order (best first) and each
+// level's list in time priority order (oldest first).
+func snapshotSide(s *bookSide, descending bool) []Order {
+	var orders []Order
+	var walk func(n *priceLevel)
+	walk = func(n *priceLevel) {
+		if n == nil {
+			return
+		}
+		if descending {
+			walk(n.right)
+		} else {
+			walk(n.left)
+		}
+		for e := n.head; e != nil; e = e.next {
+			orders = append(orders, e.order)
+		}
+		if descending {
+			walk(n.left)
 		} else {
-			return buyOrder.Price >= sellOrder.Price
+			walk(n.right)
 		}
 	}
-	return false
+	walk(s.root)
+	return orders
 }
 
+// UpdatePrice re-centers productID's book on newPrice: every order still
+// within PriceTolerance of the current market price is moved onto the new
+// price level (repriced), preserving the rest of the book untouched.
 func (ob *OrderBook) UpdatePrice(productID int, newPrice float64) {
 	ob.mutex.Lock()
 	defer ob.mutex.Unlock()
 
-	// 移动满足价格范围的订单到一级队列
-	ob.moveOrdersToPrimaryQueue(productID)
+	pb, ok := ob.books[productID]
+	if !ok {
+		return
+	}
+
+	market := marketPrice(pb)
+	bidPrices := repriceSide(pb.bids, market, newPrice, ob.PriceTolerance)
+	askPrices := repriceSide(pb.asks, market, newPrice, ob.PriceTolerance)
+	ob.journal(Command{Type: CmdUpdatePrice, ProductID: productID, NewPrice: newPrice})
 
-	// 更新价格
-	buyOrders := ob.BuyOrders[productID]
-	sellOrders := ob.SellOrders[productID]
-	for i := range buyOrders {
-		buyOrders[i].Price = newPrice
+	for _, price := range bidPrices {
+		ob.publishDepth(productID, true, price)
 	}
-	for i := range sellOrders {
-		sellOrders[i].Price = newPrice
+	for _, price := range askPrices {
+		ob.publishDepth(productID, false, price)
 	}
 }
 
-func (ob *OrderBook) moveOrdersToPrimaryQueue(productID int) {
-	secondaryBuyOrders := ob.BuyOrders[productID]
-	secondarySellOrders := ob.SellOrders[productID]
-	primaryBuyOrders := make([]Order, 0)
-	primarySellOrders := make([]Order, 0)
-
-	for _, buyOrder := range secondaryBuyOrders {
-		if math.Abs(buyOrder.Price-ob.getMarketPrice(productID)) <= ob.PriceTolerance {
-			primaryBuyOrders = append(primaryBuyOrders, buyOrder)
-		}
+func marketPrice(pb *productBook) float64 {
+	bid, hasBid := pb.bids.BestPrice()
+	ask, hasAsk := pb.asks.BestPrice()
+	switch {
+	case hasBid && hasAsk:
+		return (bid + ask) / 2
+	case hasBid:
+		return bid
+	case hasAsk:
+		return ask
+	default:
+		return 0
 	}
+}
 
-	for _, sellOrder := range secondarySellOrders {
-		if math.Abs(sellOrder.Price-ob.getMarketPrice(productID)) <= ob.PriceTolerance {
-			primarySellOrders = append(primarySellOrders, sellOrder)
+// repriceSide moves every order within tolerance of market onto newPrice.
+// Reinsertion (rather than mutating Order.Price in place) is required
+// because Price is the tree's key. It returns the distinct prices it moved
+// orders away from, followed by newPrice, so callers can publish a depth
+// update for every level it touched.
+//
+// toMove is sorted by CreateTime before reinsertion: s.byOrderID is a Go
+// map, so ranging over it directly would reinsert (and thus requeue) the
+// repriced orders in a random relative order, destroying the time priority
+// this is supposed to preserve.
+func repriceSide(s *bookSide, market, newPrice, tolerance float64) []float64 {
+	var toMove []Order
+	touched := make(map[float64]bool)
+	for _, elem := range s.byOrderID {
+		if math.Abs(elem.order.Price-market) <= tolerance {
+			toMove = append(toMove, elem.order)
+			touched[elem.order.Price] = true
 		}
 	}
-
-	ob.BuyOrders[productID] = primaryBuyOrders
-	ob.SellOrders[productID] = primarySellOrders
-}
-
-func (ob *OrderBook) getMarketPrice(productID int) float64 {
-	buyOrders := ob.BuyOrders[productID]
-	sellOrders := ob.SellOrders[productID]
-
-	if len(buyOrders) > 0 && len(sellOrders) > 0 {
-		return (buyOrders[0].Price + sellOrders[0].Price) / 2
-	} else if len(buyOrders) > 0 {
-		return buyOrders[0].Price
-	} else if len(sellOrders) > 0 {
-		return sellOrders[0].Price
+	sort.Slice(toMove, func(i, j int) bool {
+		return toMove[i].CreateTime.Before(toMove[j].CreateTime)
+	})
+	for _, order := range toMove {
+		s.removeOrder(order.ID)
+		order.Price = newPrice
+		s.insert(order)
 	}
 
-	return 0
-}
\ No newline at end of file
+	prices := make([]float64, 0, len(touched)+1)
+	for price := range touched {
+		prices = append(prices, price)
+	}
+	return append(prices, newPrice)
+}
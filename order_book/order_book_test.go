@@ -1,7 +1,6 @@
 package order_book
 
 import (
-	"fmt"
 	"math/rand"
 	"testing"
 	"time"
@@ -11,29 +10,58 @@ func TestOrderBook(t *testing.T) {
 	serializer := JSONSerializer{}
 	orderBook := NewOrderBook(serializer, 0.05)
 
-	// 添加测试订单
-	orderBook.AddBuyOrder(Order{ID: 1, Type: LimitOrder, Price: 10.0, Amount: 5.0, Priority: 3, CreateTime: time.Now(), ProductID: 1})
-	orderBook.AddBuyOrder(Order{ID: 2, Type: LimitOrder, Price: 9.5, Amount: 3.0, Priority: 5, CreateTime: time.Now(), ProductID: 1})
-	orderBook.AddBuyOrder(Order{ID: 3, Type: LimitOrder, Price: 9.0, Amount: 4.0, Priority: 4, CreateTime: time.Now(), ProductID: 1})
-
+	// 挂出卖单，构建盘口：最优价 9.0 先于 9.5 成交
 	orderBook.AddSellOrder(Order{ID: 4, Type: LimitOrder, Price: 9.5, Amount: 6.0, Priority: 2, CreateTime: time.Now(), ProductID: 1})
 	orderBook.AddSellOrder(Order{ID: 5, Type: LimitOrder, Price: 9.0, Amount: 2.0, Priority: 1, CreateTime: time.Now(), ProductID: 1})
 
-	// 进行撮合操作
-	orderBook.MatchOrders(1)
+	// 提交一笔吃单买单，按价格-时间优先撮合
+	taker := Order{ID: 1, Type: LimitOrder, Price: 10.0, Amount: 5.0, Priority: 3, CreateTime: time.Now(), ProductID: 1}
+	trades := orderBook.MatchOrders(1, taker, true)
 
-	// 打印撮合后的订单簿状态
-	fmt.Println("Final Order Book:")
-	fmt.Println("Product 1 Buy Orders:")
-	for _, order := range orderBook.BuyOrders[1] {
-		fmt.Printf("ID: %d, Type: %v, Price: %.2f, Amount: %.2f, Priority: %d, CreateTime: %v\n",
-			order.ID, order.Type, order.Price, order.Amount, order.Priority, order.CreateTime)
+	if len(trades) != 2 {
+		t.Fatalf("trades = %+v, want 2 fills (order 5 at 9.0, then order 4 at 9.5)", trades)
+	}
+	if trades[0].MakerID != 5 || trades[0].Price != 9.0 || trades[0].Amount != 2.0 {
+		t.Errorf("trades[0] = %+v, want maker 5 filled for 2.0 at the best price 9.0", trades[0])
+	}
+	if trades[1].MakerID != 4 || trades[1].Price != 9.5 || trades[1].Amount != 3.0 {
+		t.Errorf("trades[1] = %+v, want maker 4 filled for 3.0 at 9.5 once 9.0 is exhausted", trades[1])
 	}
+	for _, trade := range trades {
+		if trade.TakerID != 1 {
+			t.Errorf("trade %+v, want TakerID 1", trade)
+		}
+	}
+
+	if asks := orderBook.SellOrders(1); len(asks) != 1 || asks[0].ID != 4 || asks[0].Amount != 3.0 {
+		t.Errorf("SellOrders = %+v, want order 4 resting with 3.0 left", asks)
+	}
+	if bids := orderBook.BuyOrders(1); len(bids) != 0 {
+		t.Errorf("BuyOrders = %+v, want the fully-filled taker to leave nothing resting", bids)
+	}
+}
+
+// TestOrderBookFIFOAtSamePriceLevel pins down time priority within a single
+// price level: of two resting orders at the same price, the one placed
+// first must fill first.
+func TestOrderBookFIFOAtSamePriceLevel(t *testing.T) {
+	serializer := JSONSerializer{}
+	orderBook := NewOrderBook(serializer, 0.05)
+
+	orderBook.AddSellOrder(Order{ID: 1, Type: LimitOrder, Price: 9.0, Amount: 3.0, CreateTime: time.Now(), ProductID: 1})
+	orderBook.AddSellOrder(Order{ID: 2, Type: LimitOrder, Price: 9.0, Amount: 3.0, CreateTime: time.Now(), ProductID: 1})
+
+	taker := Order{ID: 3, Type: LimitOrder, Price: 9.0, Amount: 4.0, CreateTime: time.Now(), ProductID: 1}
+	trades := orderBook.MatchOrders(1, taker, true)
 
-	fmt.Println("Product 1 Sell Orders:")
-	for _, order := range orderBook.SellOrders[1] {
-		fmt.Printf("ID: %d, Type: %v, Price: %.2f, Amount: %.2f, Priority: %d, CreateTime: %v\n",
-			order.ID, order.Type, order.Price, order.Amount, order.Priority, order.CreateTime)
+	if len(trades) != 2 {
+		t.Fatalf("trades = %+v, want 2 fills", trades)
+	}
+	if trades[0].MakerID != 1 || trades[0].Amount != 3.0 {
+		t.Errorf("trades[0] = %+v, want the older order 1 filled first, in full", trades[0])
+	}
+	if trades[1].MakerID != 2 || trades[1].Amount != 1.0 {
+		t.Errorf("trades[1] = %+v, want the newer order 2 filled second, partially", trades[1])
 	}
 }
 
@@ -42,7 +70,7 @@ func BenchmarkMatchOrders(b *testing.B) {
 	serializer := JSONSerializer{}
 	orderBook := NewOrderBook(serializer, 0.05)
 
-	// 添加大量订单
+	// 预先挂出大量卖单构建盘口
 	numOrders := 1000000
 	for i := 0; i < numOrders; i++ {
 		order := Order{
@@ -54,18 +82,22 @@ func BenchmarkMatchOrders(b *testing.B) {
 			CreateTime: time.Now(),
 			ProductID:  1,
 		}
-		if i%2 == 0 {
-			orderBook.AddBuyOrder(order)
-		} else {
-			orderBook.AddSellOrder(order)
-		}
+		orderBook.AddSellOrder(order)
 	}
 
 	// 重置基准计数器
 	b.ResetTimer()
 
-	// 执行撮合操作
+	// 执行撮合操作：每次提交一笔吃单买单
 	for i := 0; i < b.N; i++ {
-		orderBook.MatchOrders(1)
+		taker := Order{
+			ID:         numOrders + i,
+			Type:       LimitOrder,
+			Price:      100,
+			Amount:     rand.Float64() * 10,
+			CreateTime: time.Now(),
+			ProductID:  1,
+		}
+		orderBook.MatchOrders(1, taker, true)
 	}
 }
@@ -0,0 +1,124 @@
+package order_book
+
+// SubmitResult reports the outcome of SubmitOrder: the trades it produced,
+// whether it was rejected outright, or whether it was parked as a pending
+// stop awaiting activation.
+type SubmitResult struct {
+	Trades   []Trade
+	Rejected bool
+	Reason   RejectReason
+	Held     bool // true if order.Type is StopOrder/StopLimitOrder and it was parked
+}
+
+// SubmitOrder is the entry point for order types with placement
+// preconditions: IOC, FOK, post-only, stop/stop-limit and iceberg. Plain
+// Limit and Market orders behave exactly as MatchOrders.
+func (ob *OrderBook) SubmitOrder(productID int, order Order, isBuy bool) SubmitResult {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	pb := ob.productBook(productID)
+
+	if order.Type == StopOrder || order.Type == StopLimitOrder {
+		pb.stops = append(pb.stops, pendingStop{Order: order, IsBuy: isBuy})
+		ob.journal(Command{Type: CmdAddStop, ProductID: productID, Order: order, IsBuy: isBuy})
+		return SubmitResult{Held: true}
+	}
+
+	if order.Type == IcebergOrder {
+		if order.DisplayAmount <= 0 || order.DisplayAmount > order.Amount {
+			order.DisplayAmount = order.Amount
+		}
+		order.HiddenAmount = order.Amount - order.DisplayAmount
+		order.Amount = order.DisplayAmount
+	}
+
+	makers := pb.asks
+	if !isBuy {
+		makers = pb.bids
+	}
+
+	switch order.Type {
+	case PostOnlyOrder:
+		if level := makers.best; level != nil && crosses(order, level.price, makers.isBid) {
+			return SubmitResult{Rejected: true, Reason: RejectPostOnlyCrossed}
+		}
+		return SubmitResult{Trades: ob.matchLocked(pb, productID, order, isBuy, true)}
+
+	case FOKOrder:
+		if peekFillable(makers, order) < order.Amount {
+			return SubmitResult{Rejected: true, Reason: RejectFillOrKill}
+		}
+		return SubmitResult{Trades: ob.matchLocked(pb, productID, order, isBuy, false)}
+
+	case IOCOrder:
+		return SubmitResult{Trades: ob.matchLocked(pb, productID, order, isBuy, false)}
+
+	default: // LimitOrder, MarketOrder, IcebergOrder
+		return SubmitResult{Trades: ob.matchLocked(pb, productID, order, isBuy, true)}
+	}
+}
+
+// peekFillable sums resting quantity available to taker at prices it
+// crosses, stopping as soon as a level no longer crosses (prices are walked
+// in best-first order, so nothing further out could cross either). It never
+// mutates the book, which is what makes it safe to use for FOK's
+// all-or-nothing precondition check.
+func peekFillable(makers *bookSide, taker Order) float64 {
+	var total float64
+	for level := makers.best; level != nil && total < taker.Amount; {
+		if taker.Type != MarketOrder && !crosses(taker, level.price, makers.isBid) {
+			break
+		}
+		total += level.total
+		if makers.isBid {
+			level = treePredecessor(level)
+		} else {
+			level = treeSuccessor(level)
+		}
+	}
+	return total
+}
+
+// activateStops releases any pending stop/stop-limit orders whose trigger
+// has been crossed by the latest trade price, converting each into a
+// Market (StopOrder) or Limit (StopLimitOrder) order and running it through
+// matchLocked. Releasing one stop can itself move the price and trigger
+// another, so this loops until a pass finds nothing left to release.
+func (ob *OrderBook) activateStops(pb *productBook, productID int) {
+	for {
+		idx := -1
+		for i, ps := range pb.stops {
+			if stopTriggered(ps, pb.lastTradePrice) {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			return
+		}
+
+		ps := pb.stops[idx]
+		pb.stops = append(pb.stops[:idx], pb.stops[idx+1:]...)
+		ob.journal(Command{Type: CmdRemoveStop, ProductID: productID, OrderID: ps.Order.ID})
+
+		released := ps.Order
+		if released.Type == StopOrder {
+			released.Type = MarketOrder
+		} else {
+			released.Type = LimitOrder
+		}
+		ob.matchLocked(pb, productID, released, ps.IsBuy, true)
+	}
+}
+
+// stopTriggered follows the usual stop convention: a buy stop (breakout
+// entry, or covering a short) fires once price rises to or through
+// StopPrice; a sell stop (stop-loss, or a long's protective exit) fires
+// once price falls to or through it.
+func stopTriggered(ps pendingStop, lastPrice float64) bool {
+	if ps.IsBuy {
+		return lastPrice >= ps.Order.StopPrice
+	}
+	return lastPrice <= ps.Order.StopPrice
+}
@@ -0,0 +1,149 @@
+package order_book
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubmitOrderIOC(t *testing.T) {
+	serializer := JSONSerializer{}
+	orderBook := NewOrderBook(serializer, 0.05)
+
+	orderBook.AddSellOrder(Order{ID: 1, Type: LimitOrder, Price: 9.0, Amount: 3.0, CreateTime: time.Now(), ProductID: 1})
+
+	taker := Order{ID: 2, Type: IOCOrder, Price: 9.0, Amount: 5.0, CreateTime: time.Now(), ProductID: 1}
+	result := orderBook.SubmitOrder(1, taker, true)
+
+	if len(result.Trades) != 1 || result.Trades[0].Amount != 3.0 {
+		t.Fatalf("Trades = %+v, want one 3.0 fill", result.Trades)
+	}
+	if len(orderBook.BuyOrders(1)) != 0 {
+		t.Errorf("IOC remainder must not rest, got %+v", orderBook.BuyOrders(1))
+	}
+}
+
+func TestSubmitOrderFOKRejectsWhenUnfillable(t *testing.T) {
+	serializer := JSONSerializer{}
+	orderBook := NewOrderBook(serializer, 0.05)
+
+	orderBook.AddSellOrder(Order{ID: 1, Type: LimitOrder, Price: 9.0, Amount: 3.0, CreateTime: time.Now(), ProductID: 1})
+
+	taker := Order{ID: 2, Type: FOKOrder, Price: 9.0, Amount: 5.0, CreateTime: time.Now(), ProductID: 1}
+	result := orderBook.SubmitOrder(1, taker, true)
+
+	if !result.Rejected || result.Reason != RejectFillOrKill {
+		t.Fatalf("result = %+v, want RejectFillOrKill", result)
+	}
+	if len(result.Trades) != 0 {
+		t.Errorf("a rejected FOK must not produce trades, got %+v", result.Trades)
+	}
+	if total := orderBook.SellOrders(1); len(total) != 1 || total[0].Amount != 3.0 {
+		t.Errorf("book must be untouched by a rejected FOK, got %+v", total)
+	}
+}
+
+func TestSubmitOrderFOKFillsWhenPossible(t *testing.T) {
+	serializer := JSONSerializer{}
+	orderBook := NewOrderBook(serializer, 0.05)
+
+	orderBook.AddSellOrder(Order{ID: 1, Type: LimitOrder, Price: 9.0, Amount: 3.0, CreateTime: time.Now(), ProductID: 1})
+	orderBook.AddSellOrder(Order{ID: 2, Type: LimitOrder, Price: 9.5, Amount: 4.0, CreateTime: time.Now(), ProductID: 1})
+
+	taker := Order{ID: 3, Type: FOKOrder, Price: 9.5, Amount: 5.0, CreateTime: time.Now(), ProductID: 1}
+	result := orderBook.SubmitOrder(1, taker, true)
+
+	if result.Rejected {
+		t.Fatalf("result = %+v, want a fill", result)
+	}
+	var filled float64
+	for _, trade := range result.Trades {
+		filled += trade.Amount
+	}
+	if filled != 5.0 {
+		t.Errorf("filled = %v, want 5.0", filled)
+	}
+}
+
+func TestSubmitOrderPostOnlyRejectsWhenCrossed(t *testing.T) {
+	serializer := JSONSerializer{}
+	orderBook := NewOrderBook(serializer, 0.05)
+
+	orderBook.AddSellOrder(Order{ID: 1, Type: LimitOrder, Price: 9.0, Amount: 3.0, CreateTime: time.Now(), ProductID: 1})
+
+	taker := Order{ID: 2, Type: PostOnlyOrder, Price: 9.0, Amount: 1.0, CreateTime: time.Now(), ProductID: 1}
+	result := orderBook.SubmitOrder(1, taker, true)
+
+	if !result.Rejected || result.Reason != RejectPostOnlyCrossed {
+		t.Fatalf("result = %+v, want RejectPostOnlyCrossed", result)
+	}
+	if len(orderBook.BuyOrders(1)) != 0 {
+		t.Errorf("a rejected post-only order must not rest, got %+v", orderBook.BuyOrders(1))
+	}
+}
+
+func TestSubmitOrderPostOnlyRestsWhenNotCrossed(t *testing.T) {
+	serializer := JSONSerializer{}
+	orderBook := NewOrderBook(serializer, 0.05)
+
+	orderBook.AddSellOrder(Order{ID: 1, Type: LimitOrder, Price: 9.0, Amount: 3.0, CreateTime: time.Now(), ProductID: 1})
+
+	taker := Order{ID: 2, Type: PostOnlyOrder, Price: 8.0, Amount: 1.0, CreateTime: time.Now(), ProductID: 1}
+	result := orderBook.SubmitOrder(1, taker, true)
+
+	if result.Rejected || len(result.Trades) != 0 {
+		t.Fatalf("result = %+v, want a resting order and no trades", result)
+	}
+	if bids := orderBook.BuyOrders(1); len(bids) != 1 || bids[0].Price != 8.0 {
+		t.Errorf("BuyOrders = %+v, want the post-only order resting at 8.0", bids)
+	}
+}
+
+func TestSubmitOrderStopActivation(t *testing.T) {
+	serializer := JSONSerializer{}
+	orderBook := NewOrderBook(serializer, 0.05)
+
+	// 卖出止损单：最新成交价跌破（或触及）StopPrice 时转为市价卖单
+	stop := Order{ID: 1, Type: StopOrder, StopPrice: 10.0, Amount: 2.0, CreateTime: time.Now(), ProductID: 1}
+	result := orderBook.SubmitOrder(1, stop, false)
+	if !result.Held {
+		t.Fatalf("result = %+v, want Held", result)
+	}
+
+	orderBook.AddSellOrder(Order{ID: 2, Type: LimitOrder, Price: 9.0, Amount: 5.0, CreateTime: time.Now(), ProductID: 1})
+
+	// 买单以 9.0 成交，把最新成交价压到止损触发线以下
+	taker := Order{ID: 3, Type: LimitOrder, Price: 9.0, Amount: 5.0, CreateTime: time.Now(), ProductID: 1}
+	orderBook.MatchOrders(1, taker, true)
+
+	if asks := orderBook.SellOrders(1); len(asks) != 0 {
+		t.Errorf("SellOrders = %+v, want order 2 filled and the released stop (a market order with no resting bids) to discard its remainder", asks)
+	}
+}
+
+func TestSubmitOrderIcebergReloadsAtTail(t *testing.T) {
+	serializer := JSONSerializer{}
+	orderBook := NewOrderBook(serializer, 0.05)
+
+	iceberg := Order{ID: 1, Type: IcebergOrder, Price: 9.0, Amount: 6.0, DisplayAmount: 2.0, CreateTime: time.Now(), ProductID: 1}
+	orderBook.SubmitOrder(1, iceberg, false)
+
+	other := Order{ID: 2, Type: LimitOrder, Price: 9.0, Amount: 1.0, CreateTime: time.Now(), ProductID: 1}
+	orderBook.AddSellOrder(other)
+
+	asks := orderBook.SellOrders(1)
+	if len(asks) != 2 || asks[0].ID != 1 || asks[0].Amount != 2.0 {
+		t.Fatalf("SellOrders before fill = %+v, want the iceberg's 2.0 display slice first", asks)
+	}
+
+	// 吃光冰山单当前展示的 2.0，触发其隐藏量重新挂出
+	taker := Order{ID: 3, Type: LimitOrder, Price: 9.0, Amount: 2.0, CreateTime: time.Now(), ProductID: 1}
+	orderBook.MatchOrders(1, taker, true)
+
+	asks = orderBook.SellOrders(1)
+	if len(asks) != 2 || asks[0].ID != 2 || asks[1].ID != 1 {
+		t.Fatalf("SellOrders after reload = %+v, want the iceberg requeued behind order 2", asks)
+	}
+	if asks[1].Amount != 2.0 || asks[1].HiddenAmount != 2.0 {
+		t.Errorf("reloaded iceberg slice = %+v, want Amount 2.0 with 2.0 still hidden", asks[1])
+	}
+}
@@ -0,0 +1,406 @@
+package order_book
+
+// priceLevel is a node of the per-side red-black tree, keyed by Price.
+// Every level owns a FIFO doubly-linked list of the orders resting at
+// that price so time priority within a level is a pure list operation.
+type priceLevel struct {
+	price float64
+	total float64 // aggregate resting amount at this level
+
+	head, tail *orderElement
+
+	color               rbColor
+	left, right, parent *priceLevel
+}
+
+// orderElement is an intrusive node in a priceLevel's order list. Keeping
+// the list node and the Order together lets CancelBuyOrder/CancelSellOrder
+// unlink an order in O(1) once the hash index has located it.
+type orderElement struct {
+	order      Order
+	level      *priceLevel
+	prev, next *orderElement
+}
+
+type rbColor bool
+
+const (
+	red   rbColor = true
+	black rbColor = false
+)
+
+// bookSide is one half of a product's book: a red-black tree of price
+// levels plus the hash indices that make insertion, cancellation and
+// best-price lookup O(1) in the common case.
+type bookSide struct {
+	root *priceLevel
+
+	byPrice   map[float64]*priceLevel
+	byOrderID map[int]*orderElement
+
+	// best caches the top-of-book node so BestPrice doesn't need to walk
+	// the tree. isBid sides cache the maximum price, ask sides the minimum.
+	best  *priceLevel
+	isBid bool
+}
+
+func newBookSide(isBid bool) *bookSide {
+	return &bookSide{
+		byPrice:   make(map[float64]*priceLevel),
+		byOrderID: make(map[int]*orderElement),
+		isBid:     isBid,
+	}
+}
+
+// BestPrice returns the top-of-book price and whether the side is non-empty.
+func (s *bookSide) BestPrice() (float64, bool) {
+	if s.best == nil {
+		return 0, false
+	}
+	return s.best.price, true
+}
+
+// insert appends order to the tail of its price level's list, creating the
+// level (and rebalancing the tree) if this is the first order at that price.
+func (s *bookSide) insert(order Order) *orderElement {
+	level, ok := s.byPrice[order.Price]
+	if !ok {
+		level = &priceLevel{price: order.Price}
+		s.byPrice[order.Price] = level
+		s.rbInsert(level)
+		s.updateBestOnInsert(level)
+	}
+
+	elem := &orderElement{order: order, level: level}
+	if level.tail == nil {
+		level.head, level.tail = elem, elem
+	} else {
+		elem.prev = level.tail
+		level.tail.next = elem
+		level.tail = elem
+	}
+	level.total += order.Amount
+	s.byOrderID[order.ID] = elem
+	return elem
+}
+
+// removeOrder unlinks the order in O(1) and, if it was the last order at
+// its level, removes the now-empty level from the tree in O(log n).
+func (s *bookSide) removeOrder(orderID int) (Order, bool) {
+	elem, ok := s.byOrderID[orderID]
+	if !ok {
+		return Order{}, false
+	}
+	delete(s.byOrderID, orderID)
+	s.unlinkElement(elem)
+	return elem.order, true
+}
+
+func (s *bookSide) unlinkElement(elem *orderElement) {
+	level := elem.level
+	level.total -= elem.order.Amount
+
+	if elem.prev != nil {
+		elem.prev.next = elem.next
+	} else {
+		level.head = elem.next
+	}
+	if elem.next != nil {
+		elem.next.prev = elem.prev
+	} else {
+		level.tail = elem.prev
+	}
+
+	if level.head == nil {
+		delete(s.byPrice, level.price)
+		s.rbDelete(level)
+		if s.best == level {
+			s.recomputeBest()
+		}
+	}
+}
+
+func (s *bookSide) updateBestOnInsert(level *priceLevel) {
+	if s.best == nil {
+		s.best = level
+		return
+	}
+	if s.isBid && level.price > s.best.price {
+		s.best = level
+	} else if !s.isBid && level.price < s.best.price {
+		s.best = level
+	}
+}
+
+func (s *bookSide) recomputeBest() {
+	if s.root == nil {
+		s.best = nil
+		return
+	}
+	if s.isBid {
+		s.best = treeMaximum(s.root)
+	} else {
+		s.best = treeMinimum(s.root)
+	}
+}
+
+func treeMinimum(n *priceLevel) *priceLevel {
+	for n.left != nil {
+		n = n.left
+	}
+	return n
+}
+
+func treeMaximum(n *priceLevel) *priceLevel {
+	for n.right != nil {
+		n = n.right
+	}
+	return n
+}
+
+// treeSuccessor and treePredecessor give the next level in ascending (resp.
+// descending) price order; used to walk the book level-by-level starting
+// from best without re-searching from the root each step.
+func treeSuccessor(n *priceLevel) *priceLevel {
+	if n.right != nil {
+		return treeMinimum(n.right)
+	}
+	p := n.parent
+	for p != nil && n == p.right {
+		n = p
+		p = p.parent
+	}
+	return p
+}
+
+func treePredecessor(n *priceLevel) *priceLevel {
+	if n.left != nil {
+		return treeMaximum(n.left)
+	}
+	p := n.parent
+	for p != nil && n == p.left {
+		n = p
+		p = p.parent
+	}
+	return p
+}
+
+// The remainder of this file is a standard CLRS red-black tree over
+// priceLevel nodes, ordered ascending by price. nil children are treated
+// as black leaves, matching the textbook presentation.
+
+func nodeColor(n *priceLevel) rbColor {
+	if n == nil {
+		return black
+	}
+	return n.color
+}
+
+func (s *bookSide) rotateLeft(x *priceLevel) {
+	y := x.right
+	x.right = y.left
+	if y.left != nil {
+		y.left.parent = x
+	}
+	y.parent = x.parent
+	if x.parent == nil {
+		s.root = y
+	} else if x == x.parent.left {
+		x.parent.left = y
+	} else {
+		x.parent.right = y
+	}
+	y.left = x
+	x.parent = y
+}
+
+func (s *bookSide) rotateRight(x *priceLevel) {
+	y := x.left
+	x.left = y.right
+	if y.right != nil {
+		y.right.parent = x
+	}
+	y.parent = x.parent
+	if x.parent == nil {
+		s.root = y
+	} else if x == x.parent.right {
+		x.parent.right = y
+	} else {
+		x.parent.left = y
+	}
+	y.right = x
+	x.parent = y
+}
+
+func (s *bookSide) rbInsert(z *priceLevel) {
+	var parent *priceLevel
+	cur := s.root
+	for cur != nil {
+		parent = cur
+		if z.price < cur.price {
+			cur = cur.left
+		} else {
+			cur = cur.right
+		}
+	}
+	z.parent = parent
+	switch {
+	case parent == nil:
+		s.root = z
+	case z.price < parent.price:
+		parent.left = z
+	default:
+		parent.right = z
+	}
+	z.color = red
+	s.rbInsertFixup(z)
+}
+
+func (s *bookSide) rbInsertFixup(z *priceLevel) {
+	for nodeColor(z.parent) == red {
+		grandparent := z.parent.parent
+		if z.parent == grandparent.left {
+			uncle := grandparent.right
+			if nodeColor(uncle) == red {
+				z.parent.color = black
+				uncle.color = black
+				grandparent.color = red
+				z = grandparent
+				continue
+			}
+			if z == z.parent.right {
+				z = z.parent
+				s.rotateLeft(z)
+			}
+			z.parent.color = black
+			grandparent.color = red
+			s.rotateRight(grandparent)
+		} else {
+			uncle := grandparent.left
+			if nodeColor(uncle) == red {
+				z.parent.color = black
+				uncle.color = black
+				grandparent.color = red
+				z = grandparent
+				continue
+			}
+			if z == z.parent.left {
+				z = z.parent
+				s.rotateRight(z)
+			}
+			z.parent.color = black
+			grandparent.color = red
+			s.rotateLeft(grandparent)
+		}
+	}
+	s.root.color = black
+}
+
+func (s *bookSide) transplant(u, v *priceLevel) {
+	if u.parent == nil {
+		s.root = v
+	} else if u == u.parent.left {
+		u.parent.left = v
+	} else {
+		u.parent.right = v
+	}
+	if v != nil {
+		v.parent = u.parent
+	}
+}
+
+func (s *bookSide) rbDelete(z *priceLevel) {
+	y := z
+	yOriginalColor := y.color
+	var x, xParent *priceLevel
+
+	switch {
+	case z.left == nil:
+		x, xParent = z.right, z.parent
+		s.transplant(z, z.right)
+	case z.right == nil:
+		x, xParent = z.left, z.parent
+		s.transplant(z, z.left)
+	default:
+		y = treeMinimum(z.right)
+		yOriginalColor = y.color
+		x = y.right
+		if y.parent == z {
+			xParent = y
+		} else {
+			xParent = y.parent
+			s.transplant(y, y.right)
+			y.right = z.right
+			y.right.parent = y
+		}
+		s.transplant(z, y)
+		y.left = z.left
+		y.left.parent = y
+		y.color = z.color
+	}
+
+	if yOriginalColor == black {
+		s.rbDeleteFixup(x, xParent)
+	}
+}
+
+// rbDeleteFixup restores the red-black invariants after rbDelete. x may be
+// nil (a black leaf), so its parent is threaded through explicitly.
+func (s *bookSide) rbDeleteFixup(x, parent *priceLevel) {
+	for x != s.root && nodeColor(x) == black {
+		if x == parent.left {
+			sibling := parent.right
+			if nodeColor(sibling) == red {
+				sibling.color = black
+				parent.color = red
+				s.rotateLeft(parent)
+				sibling = parent.right
+			}
+			if nodeColor(sibling.left) == black && nodeColor(sibling.right) == black {
+				sibling.color = red
+				x = parent
+				parent = x.parent
+				continue
+			}
+			if nodeColor(sibling.right) == black {
+				sibling.left.color = black
+				sibling.color = red
+				s.rotateRight(sibling)
+				sibling = parent.right
+			}
+			sibling.color = parent.color
+			parent.color = black
+			sibling.right.color = black
+			s.rotateLeft(parent)
+			x = s.root
+		} else {
+			sibling := parent.left
+			if nodeColor(sibling) == red {
+				sibling.color = black
+				parent.color = red
+				s.rotateRight(parent)
+				sibling = parent.left
+			}
+			if nodeColor(sibling.right) == black && nodeColor(sibling.left) == black {
+				sibling.color = red
+				x = parent
+				parent = x.parent
+				continue
+			}
+			if nodeColor(sibling.left) == black {
+				sibling.right.color = black
+				sibling.color = red
+				s.rotateLeft(sibling)
+				sibling = parent.left
+			}
+			sibling.color = parent.color
+			parent.color = black
+			sibling.left.color = black
+			s.rotateRight(parent)
+			x = s.root
+		}
+	}
+	if x != nil {
+		x.color = black
+	}
+}
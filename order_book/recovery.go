@@ -0,0 +1,274 @@
+package order_book
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// BookSnapshot is a point-in-time, fully serializable copy of one
+// product's book, taken at journal sequence Seq. Recover loads the newest
+// snapshot per product, then replays journal records with a higher Seq to
+// reconstruct the exact state as of the last durable record.
+type BookSnapshot struct {
+	Seq            uint64
+	ProductID      int
+	BuyOrders      []Order
+	SellOrders     []Order
+	LastTradePrice float64
+	HasTraded      bool
+	Stops          []pendingStop
+}
+
+// Snapshot captures productID's current book. The caller persists the
+// result (e.g. WriteSnapshot) and is expected to follow up with
+// Journal.Rotate and, once satisfied older segments are no longer needed,
+// Journal.Compact(snapshot.Seq).
+func (ob *OrderBook) Snapshot(productID int) BookSnapshot {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	var seq uint64
+	if ob.Journal != nil {
+		seq = ob.Journal.Seq()
+	}
+
+	pb, ok := ob.books[productID]
+	if !ok {
+		return BookSnapshot{Seq: seq, ProductID: productID}
+	}
+
+	return BookSnapshot{
+		Seq:            seq,
+		ProductID:      productID,
+		BuyOrders:      snapshotSide(pb.bids, true),
+		SellOrders:     snapshotSide(pb.asks, false),
+		LastTradePrice: pb.lastTradePrice,
+		HasTraded:      pb.hasTraded,
+		Stops:          append([]pendingStop(nil), pb.stops...),
+	}
+}
+
+func snapshotPath(dir string, productID int, seq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("snapshot-%d-%020d.snap", productID, seq))
+}
+
+// WriteSnapshot serializes snap with serializer and writes it to dir.
+func WriteSnapshot(dir string, serializer Serializer, snap BookSnapshot) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("order_book: create snapshot dir: %w", err)
+	}
+	data, err := serializer.Serialize(snap)
+	if err != nil {
+		return fmt.Errorf("order_book: serialize snapshot: %w", err)
+	}
+	return os.WriteFile(snapshotPath(dir, snap.ProductID, snap.Seq), data, 0o644)
+}
+
+// Recover rebuilds ob from the newest snapshot per product found in dir,
+// then replays every journal record with Seq greater than that snapshot's.
+// It's meant to be called once, right after NewOrderBook, before any other
+// traffic reaches the book.
+func (ob *OrderBook) Recover(dir string) error {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	snapshots, err := loadLatestSnapshots(dir, ob.Serializer)
+	if err != nil {
+		return err
+	}
+
+	snapshotSeq := make(map[int]uint64, len(snapshots))
+	for productID, snap := range snapshots {
+		pb := ob.productBook(productID)
+		for _, o := range snap.BuyOrders {
+			pb.bids.insert(o)
+		}
+		for _, o := range snap.SellOrders {
+			pb.asks.insert(o)
+		}
+		pb.lastTradePrice = snap.LastTradePrice
+		pb.hasTraded = snap.HasTraded
+		pb.stops = append(pb.stops, snap.Stops...)
+		snapshotSeq[productID] = snap.Seq
+	}
+
+	segmentPaths, err := journalSegmentPaths(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range segmentPaths {
+		cmds, err := readCommands(path, ob.Serializer)
+		if err != nil {
+			return fmt.Errorf("order_book: replay %s: %w", path, err)
+		}
+		for _, cmd := range cmds {
+			if cmd.Seq <= snapshotSeq[cmd.ProductID] {
+				continue
+			}
+			ob.replayLocked(cmd)
+		}
+	}
+
+	return nil
+}
+
+// loadLatestSnapshots reads every "snapshot-<productID>-<seq>.snap" file
+// in dir and keeps, per product, the one with the highest Seq.
+func loadLatestSnapshots(dir string, serializer Serializer) (map[int]BookSnapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("order_book: read snapshot dir: %w", err)
+	}
+
+	latest := make(map[int]BookSnapshot)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "snapshot-") || !strings.HasSuffix(name, ".snap") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("order_book: read snapshot %s: %w", name, err)
+		}
+		var snap BookSnapshot
+		if err := serializer.Deserialize(data, &snap); err != nil {
+			return nil, fmt.Errorf("order_book: decode snapshot %s: %w", name, err)
+		}
+
+		if cur, ok := latest[snap.ProductID]; !ok || snap.Seq > cur.Seq {
+			latest[snap.ProductID] = snap
+		}
+	}
+	return latest, nil
+}
+
+// journalSegmentPaths lists rotated-out segments followed by the active
+// segment, in replay order.
+func journalSegmentPaths(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("order_book: read journal dir: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, "segment-") && strings.HasSuffix(name, ".log") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names) // zero-padded seq orders rotated segments; "segment-active.log" sorts last
+
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = filepath.Join(dir, name)
+	}
+	return paths, nil
+}
+
+// readCommands decodes every length-prefixed record in path.
+func readCommands(path string, serializer Serializer) ([]Command, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var cmds []Command
+	var lenPrefix [4]byte
+	for {
+		if _, err := io.ReadFull(file, lenPrefix[:]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		payload := make([]byte, binary.BigEndian.Uint32(lenPrefix[:]))
+		if _, err := io.ReadFull(file, payload); err != nil {
+			return nil, err
+		}
+
+		var cmd Command
+		if err := serializer.Deserialize(payload, &cmd); err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, cmd)
+	}
+	return cmds, nil
+}
+
+// replayLocked applies a single journaled command to reconstruct state.
+// Unlike the live path, it never re-journals (Recover runs before the book
+// is attached to new traffic) and trades are applied directly to the
+// maker's resting amount rather than rerun through the matching loop.
+func (ob *OrderBook) replayLocked(cmd Command) {
+	pb := ob.productBook(cmd.ProductID)
+
+	switch cmd.Type {
+	case CmdAddBuyOrder:
+		pb.bids.insert(cmd.Order)
+	case CmdAddSellOrder:
+		pb.asks.insert(cmd.Order)
+	case CmdCancelBuyOrder:
+		pb.bids.removeOrder(cmd.OrderID)
+	case CmdCancelSellOrder:
+		pb.asks.removeOrder(cmd.OrderID)
+	case CmdUpdatePrice:
+		market := marketPrice(pb)
+		repriceSide(pb.bids, market, cmd.NewPrice, ob.PriceTolerance)
+		repriceSide(pb.asks, market, cmd.NewPrice, ob.PriceTolerance)
+	case CmdTrade:
+		applyTradeReplay(pb, cmd.Trade)
+	case CmdAddStop:
+		pb.stops = append(pb.stops, pendingStop{Order: cmd.Order, IsBuy: cmd.IsBuy})
+	case CmdRemoveStop:
+		for i, ps := range pb.stops {
+			if ps.Order.ID == cmd.OrderID {
+				pb.stops = append(pb.stops[:i], pb.stops[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// applyTradeReplay decrements the maker side of a journaled trade. The
+// maker is looked up by ID on whichever side currently holds it; which
+// side that is depends on whether the original taker was a buy or a sell,
+// which the Command doesn't carry, so both are checked.
+func applyTradeReplay(pb *productBook, trade Trade) {
+	pb.lastTradePrice = trade.Price
+	pb.hasTraded = true
+
+	if elem, ok := pb.bids.byOrderID[trade.MakerID]; ok {
+		applyFillReplay(pb.bids, elem, trade)
+		return
+	}
+	if elem, ok := pb.asks.byOrderID[trade.MakerID]; ok {
+		applyFillReplay(pb.asks, elem, trade)
+	}
+}
+
+func applyFillReplay(side *bookSide, elem *orderElement, trade Trade) {
+	elem.order.Amount -= trade.Amount
+	elem.level.total -= trade.Amount
+	if elem.order.Amount <= 0 {
+		reloadIceberg(side, elem)
+	}
+}